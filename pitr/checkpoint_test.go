@@ -0,0 +1,78 @@
+package pitr
+
+import "testing"
+
+func TestCheckpointDisabledWithoutCheckpointFile(t *testing.T) {
+	r := &PITR{cfg: &Config{}, storage: newLocalStorage(t.TempDir())}
+
+	cp, err := r.loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp != nil {
+		t.Errorf("loadCheckpoint() = %+v, want nil when CheckpointFile is unset", cp)
+	}
+
+	if err := r.saveCheckpoint(&checkpoint{LastFile: "f", LastCommitTS: 1}); err != nil {
+		t.Errorf("saveCheckpoint should be a no-op when CheckpointFile is unset, got %v", err)
+	}
+}
+
+func TestCheckpointSaveThenLoadRoundTrips(t *testing.T) {
+	r := &PITR{
+		cfg:     &Config{CheckpointFile: "cp.json"},
+		storage: newLocalStorage(t.TempDir()),
+	}
+
+	want := &checkpoint{LastFile: "binlog-0001", LastCommitTS: 42}
+	if err := r.saveCheckpoint(want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.LastFile != want.LastFile || got.LastCommitTS != want.LastCommitTS {
+		t.Errorf("loadCheckpoint() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResumeFromCheckpointAdvancesStartTSO(t *testing.T) {
+	r := &PITR{
+		cfg:     &Config{CheckpointFile: "cp.json"},
+		storage: newLocalStorage(t.TempDir()),
+	}
+	if err := r.saveCheckpoint(&checkpoint{LastFile: "binlog-0001", LastCommitTS: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	startTSO, cp, err := r.resumeFromCheckpoint(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startTSO != 101 {
+		t.Errorf("resumeFromCheckpoint(10) startTSO = %d, want 101 (just after the already-merged commit ts)", startTSO)
+	}
+	if cp == nil || cp.LastCommitTS != 100 {
+		t.Errorf("resumeFromCheckpoint(10) cp = %+v, want the loaded checkpoint", cp)
+	}
+}
+
+func TestResumeFromCheckpointKeepsLaterStartTSO(t *testing.T) {
+	r := &PITR{
+		cfg:     &Config{CheckpointFile: "cp.json"},
+		storage: newLocalStorage(t.TempDir()),
+	}
+	if err := r.saveCheckpoint(&checkpoint{LastFile: "binlog-0001", LastCommitTS: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	startTSO, _, err := r.resumeFromCheckpoint(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startTSO != 200 {
+		t.Errorf("resumeFromCheckpoint(200) startTSO = %d, want 200 (already past the checkpoint)", startTSO)
+	}
+}
@@ -0,0 +1,50 @@
+package pitr
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Config holds the configuration of pitr.
+type Config struct {
+	Dir      string `toml:"data-dir" json:"data-dir"`
+	StartTSO int64  `toml:"start-tso" json:"start-tso"`
+	StopTSO  int64  `toml:"stop-tso" json:"stop-tso"`
+
+	// StartDatetime and StopDatetime are human readable alternatives to
+	// StartTSO/StopTSO, e.g. "2023-06-01 12:00:00". When set, they are
+	// resolved into TSOs before PITR.Process starts working.
+	StartDatetime string `toml:"start-datetime" json:"start-datetime"`
+	StopDatetime  string `toml:"stop-datetime" json:"stop-datetime"`
+
+	PDURLs string `toml:"pd-urls" json:"pd-urls"`
+
+	// CheckpointFile is where PITR persists its progress so a restarted
+	// Process can resume from the last durable checkpoint instead of
+	// re-reading the whole data-dir. Checkpoint/resume support is disabled
+	// when this is left empty: there is no safe default, since it resolves
+	// through the same storage rooted at data-dir that holds the binlog
+	// files themselves.
+	CheckpointFile string `toml:"checkpoint-file" json:"checkpoint-file"`
+
+	IgnoreDBs    string `toml:"ignore-dbs" json:"ignore-dbs"`
+	IgnoreTables string `toml:"ignore-tables" json:"ignore-tables"`
+	DoDBs        string `toml:"do-dbs" json:"do-dbs"`
+	DoTables     string `toml:"do-tables" json:"do-tables"`
+
+	// StrictValidation makes Validate fail Process instead of only logging
+	// when a table in the TSO window can't be safely replicated.
+	StrictValidation bool `toml:"strict-validation" json:"strict-validation"`
+
+	schemaFile     string
+	reserveTempDir bool
+}
+
+// String implements fmt.Stringer so a Config can be logged directly.
+func (c *Config) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("marshal config failed: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,153 @@
+package pitr
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	pd "github.com/pingcap/pd/client"
+	"github.com/pingcap/tidb-binlog/pkg/flags"
+	"go.uber.org/zap"
+)
+
+// datetimeFormat is the layout accepted by --start-datetime/--stop-datetime,
+// mirroring the format BR uses for its datetime based PITR restore.
+const datetimeFormat = "2006-01-02 15:04:05"
+
+// physicalShiftBits is the number of bits the physical part of a TSO is
+// shifted by, leaving room for the logical counter.
+const physicalShiftBits = 18
+
+// composeTSO builds a TSO from a physical time in milliseconds, matching the
+// encoding TiDB/PD use: physical<<18 | logical.
+func composeTSO(physical int64) int64 {
+	return physical << physicalShiftBits
+}
+
+// parseDatetime parses a human readable datetime string into the physical
+// time in milliseconds used to compose a TSO.
+func parseDatetime(datetime string) (int64, error) {
+	t, err := time.ParseInLocation(datetimeFormat, datetime, time.Local)
+	if err != nil {
+		return 0, errors.Annotatef(err, "parse datetime %s failed, expect format %s", datetime, datetimeFormat)
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
+
+// tsoFromPD resolves datetime against PD's own clock: it reads PD's current
+// (physical, logical) TSO, diffs it against the local wall clock to get PD's
+// clock offset, and applies that offset to the parsed datetime before
+// composing a TSO. This is what keeps the result consistent with the
+// cluster's own clock instead of the local machine's, which a pure local
+// parse (tsoFromFiles' fallback) cannot do.
+func tsoFromPD(pdURLs string, datetime string) (int64, error) {
+	physical, err := parseDatetime(datetime)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	urlv, err := flags.NewURLsValue(pdURLs)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	pdClient, err := pd.NewClient(urlv.StringSlice(), pd.SecurityOption{})
+	if err != nil {
+		return 0, errors.Annotate(err, "create pd client failed")
+	}
+	defer pdClient.Close()
+
+	pdPhysical, _, err := pdClient.GetTS(context.Background())
+	if err != nil {
+		return 0, errors.Annotate(err, "get ts from pd failed")
+	}
+	offset := pdPhysical - time.Now().UnixNano()/int64(time.Millisecond)
+
+	return composeTSO(physical + offset), nil
+}
+
+// tsoFromFiles resolves a datetime into the TSO of the first binlog file
+// that starts at or after the requested time, by binary searching the
+// already time-sorted files. It is used when no PD address is given.
+//
+// No file may start at or after target: the newest file is usually still
+// being written, so the requested time falls inside (or after) it with no
+// next file boundary to round up to. What that should resolve to depends on
+// which of StartTSO/StopTSO is being resolved, which is why forStart is
+// passed in rather than guessed from context:
+//   - for a stop time, there is no more data past it yet, so reporting 0
+//     (the sentinel isAcceptableBinlog/Process already treat as "unbounded")
+//     is correct: everything available up to now satisfies "stop at or after
+//     target".
+//   - for a start time, 0 means the opposite, "no start given", which would
+//     make Process replay from the very first binlog ever written instead of
+//     honoring the requested start. So the start case returns target itself:
+//     events are still filtered by commitTs >= startTs downstream, it just
+//     can't be rounded up to a file boundary the way the stop case can.
+func tsoFromFiles(storage ExternalStorage, files []string, datetime string, forStart bool) (int64, error) {
+	physical, err := parseDatetime(datetime)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	target := composeTSO(physical)
+
+	if len(files) == 0 {
+		return 0, errors.New("no binlog file to search for the requested datetime")
+	}
+
+	// A read error during the search must abort it rather than guess false:
+	// guessing breaks the monotonicity sort.Search assumes and can return a
+	// wrong file boundary instead of surfacing the failure, which matters
+	// once binlogs may live on object storage where reads can fail
+	// transiently. Once searchErr is set, the predicate returns true for
+	// every remaining probe so the search converges immediately.
+	var searchErr error
+	idx := sort.Search(len(files), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		commitTS, _, err := getFirstBinlogCommitTSAndFileSize(storage, files[i])
+		if err != nil {
+			searchErr = errors.Annotatef(err, "get first binlog commit ts for %s failed", files[i])
+			return true
+		}
+		return commitTS >= target
+	})
+	if searchErr != nil {
+		return 0, errors.Trace(searchErr)
+	}
+
+	if idx == len(files) {
+		if forStart {
+			return target, nil
+		}
+		return 0, nil
+	}
+	commitTS, _, err := getFirstBinlogCommitTSAndFileSize(storage, files[idx])
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return commitTS, nil
+}
+
+// resolveTSO turns a TSO/datetime pair from Config into the TSO that should
+// actually be used, preferring the explicit TSO when both are given.
+// forStart says whether tso/datetime is StartTSO/StartDatetime rather than
+// StopTSO/StopDatetime, since tsoFromFiles resolves the "falls past every
+// known file" case differently depending on which end of the window it is.
+func (r *PITR) resolveTSO(tso int64, datetime string, files []string, forStart bool) (int64, error) {
+	if tso != 0 || len(datetime) == 0 {
+		return tso, nil
+	}
+
+	if len(r.cfg.PDURLs) != 0 {
+		resolved, err := tsoFromPD(r.cfg.PDURLs, datetime)
+		if err == nil {
+			return resolved, nil
+		}
+		log.Warn("resolve datetime via pd failed, fall back to searching binlog files", zap.String("datetime", datetime), zap.Error(err))
+	}
+
+	return tsoFromFiles(r.storage, files, datetime, forStart)
+}
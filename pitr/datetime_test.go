@@ -0,0 +1,36 @@
+package pitr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDatetimeMatchesUnixMillis(t *testing.T) {
+	got, err := parseDatetime("2023-06-01 12:00:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local).UnixNano() / int64(time.Millisecond)
+	if got != want {
+		t.Errorf("parseDatetime(...) = %d, want %d", got, want)
+	}
+}
+
+func TestParseDatetimeRejectsWrongFormat(t *testing.T) {
+	if _, err := parseDatetime("2023/06/01 12:00:00"); err == nil {
+		t.Error("expected an error for a datetime that doesn't match datetimeFormat")
+	}
+}
+
+func TestComposeTSOShiftsPhysicalIntoHighBits(t *testing.T) {
+	physical := int64(1685620800000)
+	tso := composeTSO(physical)
+
+	if tso>>physicalShiftBits != physical {
+		t.Errorf("composeTSO(%d) = %d, expected physical to round-trip out of the high bits", physical, tso)
+	}
+	if tso&(1<<physicalShiftBits-1) != 0 {
+		t.Errorf("composeTSO(%d) = %d, expected the logical bits to be zero", physical, tso)
+	}
+}
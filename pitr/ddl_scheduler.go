@@ -0,0 +1,208 @@
+package pitr
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/model"
+	"go.uber.org/zap"
+)
+
+// historyDDLConcurrency bounds how many independent DDL jobs may execute at
+// the same time.
+const historyDDLConcurrency = 8
+
+// ddlObject is a schema, or a single table within a schema, touched by a DDL
+// job. table is empty for schema-level jobs such as CREATE/DROP DATABASE,
+// which must block every table in that schema.
+type ddlObject struct {
+	schema string
+	table  string
+}
+
+// ddlJobObjects returns every object job touches, including both the
+// source and destination of a rename, so the scheduler can detect conflicts
+// with any other job racing to touch the same schema or table.
+func ddlJobObjects(job *model.Job) []ddlObject {
+	switch job.Type {
+	case model.ActionCreateSchema, model.ActionDropSchema, model.ActionModifySchemaCharsetAndCollate:
+		return []ddlObject{{schema: job.SchemaName}}
+	case model.ActionRenameTable:
+		objs := []ddlObject{{schema: job.SchemaName, table: job.TableName}}
+		var oldSchemaID int64
+		var oldSchemaName model.CIStr
+		if err := job.DecodeArgs(&oldSchemaID, &oldSchemaName); err != nil {
+			log.Error("decode rename table job args failed, treating it as schema-internal only", zap.Int64("job-id", job.ID), zap.Error(err))
+		} else if len(oldSchemaName.L) != 0 {
+			objs = append(objs, ddlObject{schema: oldSchemaName.L, table: job.TableName})
+		}
+		return objs
+	default:
+		return []ddlObject{{schema: job.SchemaName, table: job.TableName}}
+	}
+}
+
+// ddlScheduler dispatches a stream of DDL jobs to a fixed pool of workers,
+// running independent jobs concurrently while making sure two jobs that
+// touch the same schema or table still run in their original order, which
+// is what preserves the schema-version ordering invariant. Jobs are fed in
+// via add, which workers may already be draining while more jobs are still
+// arriving; closeInput tells next that no more jobs are coming.
+type ddlScheduler struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	pending []*model.Job
+	closed  bool
+
+	runningSchemas map[string]int
+	runningTables  map[string]int
+}
+
+func newDDLScheduler() *ddlScheduler {
+	s := &ddlScheduler{
+		runningSchemas: make(map[string]int),
+		runningTables:  make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// add enqueues job, waking any worker blocked in next().
+func (s *ddlScheduler) add(job *model.Job) {
+	s.mu.Lock()
+	s.pending = append(s.pending, job)
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// closeInput tells next() that no further jobs will be added, so it can stop
+// waiting once pending drains instead of blocking forever.
+func (s *ddlScheduler) closeInput() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// conflicts reports whether any object in objs is blocked by a job that is
+// currently running.
+func (s *ddlScheduler) conflicts(objs []ddlObject) bool {
+	for _, o := range objs {
+		if s.runningSchemas[o.schema] > 0 {
+			return true
+		}
+		if len(o.table) == 0 {
+			for key, n := range s.runningTables {
+				if n > 0 && strings.HasPrefix(key, o.schema+".") {
+					return true
+				}
+			}
+			continue
+		}
+		if s.runningTables[o.schema+"."+o.table] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// next blocks until a pending job can run without overlapping any currently
+// running job, marks its objects as running, and returns it. It returns nil
+// once closeInput has been called and there is no pending work left.
+func (s *ddlScheduler) next() (*model.Job, []ddlObject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		// Objects of pending jobs skipped in this pass stay blocked for the
+		// jobs queued after them, so conflicting jobs keep their order.
+		waiting := newDDLScheduler()
+		for i, job := range s.pending {
+			objs := ddlJobObjects(job)
+			if s.conflicts(objs) || waiting.conflicts(objs) {
+				waiting.markRunning(objs)
+				continue
+			}
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.markRunning(objs)
+			return job, objs
+		}
+		if s.closed && len(s.pending) == 0 {
+			return nil, nil
+		}
+		s.cond.Wait()
+	}
+}
+
+func (s *ddlScheduler) markRunning(objs []ddlObject) {
+	for _, o := range objs {
+		if len(o.table) == 0 {
+			s.runningSchemas[o.schema]++
+		} else {
+			s.runningTables[o.schema+"."+o.table]++
+		}
+	}
+}
+
+// done marks objs no longer running and wakes workers blocked in next().
+func (s *ddlScheduler) done(objs []ddlObject) {
+	s.mu.Lock()
+	for _, o := range objs {
+		if len(o.table) == 0 {
+			s.runningSchemas[o.schema]--
+		} else {
+			s.runningTables[o.schema+"."+o.table]--
+		}
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// executeDDLJobsConcurrently runs jobs received off jobCh through a bounded
+// worker pool, dispatching each job as soon as it no longer overlaps with
+// whatever is currently running, and returns the first error encountered, if
+// any. Workers may start running early jobs while later ones are still
+// arriving on jobCh.
+func executeDDLJobsConcurrently(jobCh <-chan *model.Job) error {
+	scheduler := newDDLScheduler()
+	go func() {
+		for job := range jobCh {
+			scheduler.add(job)
+		}
+		scheduler.closeInput()
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	worker := func() {
+		defer wg.Done()
+		for {
+			job, objs := scheduler.next()
+			if job == nil {
+				return
+			}
+			log.Info("execute history ddl job", zap.Int64("job-id", job.ID), zap.String("schema", job.SchemaName), zap.String("table", job.TableName))
+			if err := ddlHandle.ExecuteHistoryDDLs([]*model.Job{job}); err != nil {
+				errOnce.Do(func() {
+					firstErr = errors.Annotatef(err, "execute ddl job %d failed", job.ID)
+				})
+			}
+			scheduler.done(objs)
+		}
+	}
+
+	wg.Add(historyDDLConcurrency)
+	for i := 0; i < historyDDLConcurrency; i++ {
+		go worker()
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,127 @@
+package pitr
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/parser/model"
+)
+
+func newTestJob(id int64, typ model.ActionType, schema, table string) *model.Job {
+	return &model.Job{
+		ID:         id,
+		Type:       typ,
+		SchemaName: schema,
+		TableName:  table,
+		BinlogInfo: &model.HistoryInfo{},
+	}
+}
+
+func TestDDLJobObjectsRenameTableCoversOldAndNewSchema(t *testing.T) {
+	job := newTestJob(1, model.ActionRenameTable, "new_db", "t1")
+	rawArgs, err := json.Marshal([]interface{}{int64(2), model.NewCIStr("old_db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.RawArgs = rawArgs
+
+	objs := ddlJobObjects(job)
+	if len(objs) != 2 {
+		t.Fatalf("expected rename to touch both the old and new schema, got %v", objs)
+	}
+	if objs[0] != (ddlObject{schema: "new_db", table: "t1"}) {
+		t.Errorf("expected first object to be the destination table, got %v", objs[0])
+	}
+	if objs[1] != (ddlObject{schema: "old_db", table: "t1"}) {
+		t.Errorf("expected second object to be the source table, got %v", objs[1])
+	}
+}
+
+func TestDDLJobObjectsSchemaLevelJobHasNoTable(t *testing.T) {
+	job := newTestJob(1, model.ActionDropSchema, "db1", "")
+	objs := ddlJobObjects(job)
+	if len(objs) != 1 || objs[0].table != "" || objs[0].schema != "db1" {
+		t.Fatalf("expected a single schema-level object, got %v", objs)
+	}
+}
+
+func TestDDLSchedulerConflictsSchemaBlocksItsTables(t *testing.T) {
+	s := newDDLScheduler()
+	s.markRunning([]ddlObject{{schema: "db1"}})
+
+	if !s.conflicts([]ddlObject{{schema: "db1", table: "t1"}}) {
+		t.Error("a running schema-level job should block any table in that schema")
+	}
+	if s.conflicts([]ddlObject{{schema: "db2", table: "t1"}}) {
+		t.Error("a running schema-level job should not block a different schema")
+	}
+}
+
+func TestDDLSchedulerConflictsTableBlocksItsSchema(t *testing.T) {
+	s := newDDLScheduler()
+	s.markRunning([]ddlObject{{schema: "db1", table: "t1"}})
+
+	if !s.conflicts([]ddlObject{{schema: "db1"}}) {
+		t.Error("a running table-level job should block a schema-level job over the same schema")
+	}
+	if s.conflicts([]ddlObject{{schema: "db1", table: "t2"}}) {
+		t.Error("a running table-level job should not block an unrelated table in the same schema")
+	}
+}
+
+// TestDDLSchedulerOrdersConflictingJobs checks that two jobs touching the
+// same table never run at the same time, while unrelated jobs can.
+func TestDDLSchedulerOrdersConflictingJobs(t *testing.T) {
+	s := newDDLScheduler()
+	for _, job := range []*model.Job{
+		newTestJob(1, model.ActionAddColumn, "db1", "t1"),
+		newTestJob(2, model.ActionAddColumn, "db1", "t1"),
+		newTestJob(3, model.ActionAddColumn, "db1", "t2"),
+	} {
+		s.add(job)
+	}
+	s.closeInput()
+
+	var mu sync.Mutex
+	running := map[string]bool{}
+	var conflictSeen bool
+
+	worker := func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		for {
+			job, objs := s.next()
+			if job == nil {
+				return
+			}
+			key := objs[0].schema + "." + objs[0].table
+
+			mu.Lock()
+			if running[key] {
+				conflictSeen = true
+			}
+			running[key] = true
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running[key] = false
+			mu.Unlock()
+
+			s.done(objs)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		go worker(&wg)
+	}
+	wg.Wait()
+
+	if conflictSeen {
+		t.Error("two jobs touching the same table ran concurrently")
+	}
+}
@@ -29,7 +29,14 @@ type historyDDLHandler struct {
 
 func (h *historyDDLHandler) execute() (err error) {
 	if len(h.ddlJobs) != 0 {
-		return ddlHandle.ExecuteHistoryDDLs(h.ddlJobs)
+		jobCh := make(chan *model.Job)
+		go func() {
+			defer close(jobCh)
+			for _, job := range h.ddlJobs {
+				jobCh <- job
+			}
+		}()
+		return executeDDLJobsConcurrently(jobCh)
 	}
 	for _, ddl := range h.ddlSQLs {
 		err = ddlHandle.ExecuteDDL("", ddl)
@@ -45,6 +52,11 @@ type PITR struct {
 	cfg *Config
 
 	filter *filter.Filter
+
+	// storage is the backend cfg.Dir resolves to, supporting local paths as
+	// well as s3://, gcs:// and azblob:// so PITR can run directly against
+	// cloud-archived drainer binlogs.
+	storage ExternalStorage
 }
 
 // New creates a PITR object.
@@ -53,15 +65,21 @@ func New(cfg *Config) (*PITR, error) {
 
 	filter := filter.NewFilter(cfg.IgnoreDBs, cfg.IgnoreTables, cfg.DoDBs, cfg.DoTables)
 
+	storage, err := newExternalStorage(cfg.Dir)
+	if err != nil {
+		return nil, errors.Annotate(err, "open storage for data-dir failed")
+	}
+
 	return &PITR{
-		cfg:    cfg,
-		filter: filter,
+		cfg:     cfg,
+		filter:  filter,
+		storage: storage,
 	}, nil
 }
 
 // Process runs the main procedure.
 func (r *PITR) Process() error {
-	files, err := searchFiles(r.cfg.Dir)
+	files, err := searchFiles(r.storage)
 	if err != nil {
 		return errors.Annotate(err, fmt.Sprintf("search files in directory %s failed", r.cfg.Dir))
 	}
@@ -69,27 +87,43 @@ func (r *PITR) Process() error {
 		return errors.Annotate(err, fmt.Sprintf("no file is searched in directory %s", r.cfg))
 	}
 
-	files, fileSize, err := filterFiles(files, r.cfg.StartTSO, r.cfg.StopTSO)
+	startTSO, err := r.resolveTSO(r.cfg.StartTSO, r.cfg.StartDatetime, files, true)
+	if err != nil {
+		return errors.Annotate(err, "resolve start-datetime failed")
+	}
+	stopTSO, err := r.resolveTSO(r.cfg.StopTSO, r.cfg.StopDatetime, files, false)
+	if err != nil {
+		return errors.Annotate(err, "resolve stop-datetime failed")
+	}
+
+	files, fileSize, err := filterFiles(r.storage, files, startTSO, stopTSO)
 	if err != nil {
 		return errors.Annotate(err, "filterFiles failed")
 	}
 	if len(files) == 0 {
-		return errors.Annotate(err, fmt.Sprintf("no files remained between the time interval [%d, %d]", r.cfg.StartTSO, r.cfg.StopTSO))
+		return errors.Annotate(err, fmt.Sprintf("no files remained between the time interval [%d, %d]", startTSO, stopTSO))
 	}
 
-	startTSO := r.cfg.StartTSO
 	if startTSO == 0 {
-		startTSO, _, err = getFirstBinlogCommitTSAndFileSize(files[0])
+		startTSO, _, err = getFirstBinlogCommitTSAndFileSize(r.storage, files[0])
 		if err != nil {
 			return errors.Annotate(err, "get first binlog commit ts failed")
 		}
 	}
 
+	startTSO, resumeCP, err := r.resumeFromCheckpoint(startTSO)
+	if err != nil {
+		return errors.Annotate(err, "resume from checkpoint failed")
+	}
+
 	historyDDLs, err := r.fetchDDLBeforeStartTSO(startTSO)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	merge, err := NewMerge(files, fileSize)
+	if err = r.Validate(historyDDLs); err != nil {
+		return errors.Trace(err)
+	}
+	merge, err := NewMerge(r.storage, files, fileSize, r.saveCheckpoint, resumeCP)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -97,11 +131,11 @@ func (r *PITR) Process() error {
 	if err = historyDDLs.execute(); err != nil {
 		return err
 	}
-	noEventIsFound, err := merge.Map(startTSO, r.cfg.StopTSO)
+	noEventIsFound, err := merge.Map(startTSO, stopTSO)
 	if err != nil {
 		return errors.Trace(err)
 	} else if noEventIsFound {
-		log.Info(fmt.Sprintf("no event is found between [%d, %d]", startTSO, r.cfg.StopTSO))
+		log.Info(fmt.Sprintf("no event is found between [%d, %d]", startTSO, stopTSO))
 		return nil
 	}
 	if err = historyDDLs.execute(); err != nil {
@@ -144,6 +178,7 @@ func (r *PITR) loadHistoryDDLJobs(beginTS int64) ([]*model.Job, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+
 	allJobs, err := snapMeta.GetAllHistoryDDLJobs()
 	if err != nil {
 		return nil, errors.Trace(err)
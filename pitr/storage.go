@@ -0,0 +1,330 @@
+package pitr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pingcap/errors"
+	"google.golang.org/api/iterator"
+)
+
+// ErrFileNotExist is the error ReadRange returns when name does not exist,
+// normalizing the various provider-specific not-found errors (os.ErrNotExist,
+// s3.ErrCodeNoSuchKey, storage.ErrObjectNotExist, azblob's ServiceCodeBlobNotFound)
+// into one sentinel callers can check for with errors.Cause regardless of
+// which backend they're talking to.
+var ErrFileNotExist = errors.New("file does not exist")
+
+// ExternalStorage abstracts the place binlog input is read from and the
+// merged output is written to, so PITR can work against s3://, gcs://,
+// azblob:// or a local path transparently. It is analogous to the
+// storage.ExternalStorage abstraction BR uses to ingest backup data.
+type ExternalStorage interface {
+	// ReadRange reads [offset, offset+length) of name, so a caller such as
+	// getFirstBinlogCommitTSAndFileSize can sample the head of a file without
+	// downloading it whole. length <= 0 means read until EOF.
+	ReadRange(ctx context.Context, name string, offset, length int64) ([]byte, error)
+	// WalkDir calls fn once for every file under the storage root, used by
+	// searchFiles/filterFiles to enumerate binlog files.
+	WalkDir(ctx context.Context, fn func(name string, size int64) error) error
+	// Create opens name for streamed writing, truncating it if it exists,
+	// used to write the reduced/merged binlog output.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+// newExternalStorage opens an ExternalStorage for rawURL, dispatching on its
+// scheme. A path with no scheme (or scheme "file") is treated as local disk.
+func newExternalStorage(rawURL string) (ExternalStorage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Annotatef(err, "parse storage url %s failed", rawURL)
+	}
+
+	switch u.Scheme {
+	case "":
+		return newLocalStorage(rawURL), nil
+	case "file":
+		return newLocalStorage(u.Path), nil
+	case "s3":
+		return newS3Storage(u)
+	case "gcs", "gs":
+		return newGCSStorage(u)
+	case "azblob", "azure":
+		return newAzblobStorage(u)
+	default:
+		return nil, errors.Errorf("unsupported storage scheme %q in %s", u.Scheme, rawURL)
+	}
+}
+
+// localStorage implements ExternalStorage on top of the local filesystem.
+type localStorage struct {
+	base string
+}
+
+func newLocalStorage(base string) *localStorage {
+	return &localStorage{base: base}
+}
+
+func (l *localStorage) path(name string) string {
+	return filepath.Join(l.base, name)
+}
+
+func (l *localStorage) ReadRange(_ context.Context, name string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(l.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Trace(ErrFileNotExist)
+		}
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if length <= 0 {
+		data, err := ioutil.ReadAll(f)
+		return data, errors.Trace(err)
+	}
+	data := make([]byte, length)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, errors.Trace(err)
+	}
+	return data[:n], nil
+}
+
+func (l *localStorage) WalkDir(_ context.Context, fn func(name string, size int64) error) error {
+	return filepath.Walk(l.base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.base, path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return fn(rel, info.Size())
+	})
+}
+
+func (l *localStorage) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	path := l.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	f, err := os.Create(path)
+	return f, errors.Trace(err)
+}
+
+// s3Storage implements ExternalStorage on top of S3-compatible object
+// storage, addressed as s3://bucket/prefix.
+type s3Storage struct {
+	svc    *s3.S3
+	bucket string
+	prefix string
+}
+
+func newS3Storage(u *url.URL) (*s3Storage, error) {
+	sess, err := session.NewSession(aws.NewConfig())
+	if err != nil {
+		return nil, errors.Annotate(err, "create s3 session failed")
+	}
+	return &s3Storage{
+		svc:    s3.New(sess),
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (s *s3Storage) key(name string) string {
+	return filepath.Join(s.prefix, name)
+}
+
+func (s *s3Storage) ReadRange(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	}
+	if length > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	out, err := s.svc.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return nil, errors.Trace(ErrFileNotExist)
+		}
+		return nil, errors.Annotatef(err, "get s3 object %s failed", name)
+	}
+	defer out.Body.Close()
+	data, err := ioutil.ReadAll(out.Body)
+	return data, errors.Trace(err)
+}
+
+func (s *s3Storage) WalkDir(ctx context.Context, fn func(name string, size int64) error) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}
+	var walkErr error
+	err := s.svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(strings.TrimPrefix(*obj.Key, s.prefix), "/")
+			if walkErr = fn(rel, *obj.Size); walkErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if walkErr != nil {
+		return errors.Trace(walkErr)
+	}
+	return errors.Trace(err)
+}
+
+func (s *s3Storage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	return newS3Writer(ctx, s.svc, s.bucket, s.key(name)), nil
+}
+
+// gcsStorage implements ExternalStorage on top of Google Cloud Storage,
+// addressed as gcs://bucket/prefix.
+type gcsStorage struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newGCSStorage(u *url.URL) (*gcsStorage, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, errors.Annotate(err, "create gcs client failed")
+	}
+	return &gcsStorage{
+		client: client,
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (g *gcsStorage) object(name string) *storage.ObjectHandle {
+	return g.client.Bucket(g.bucket).Object(filepath.Join(g.prefix, name))
+}
+
+func (g *gcsStorage) ReadRange(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	// NewRangeReader's own length <= 0 convention is the opposite of
+	// ReadRange's: 0 means "read zero bytes" there, and only a negative
+	// length means "to EOF". Translate so length <= 0 means "to EOF" here
+	// too, matching every other backend.
+	if length <= 0 {
+		length = -1
+	}
+	r, err := g.object(name).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return nil, errors.Trace(ErrFileNotExist)
+		}
+		return nil, errors.Annotatef(err, "read gcs object %s failed", name)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	return data, errors.Trace(err)
+}
+
+func (g *gcsStorage) WalkDir(ctx context.Context, fn func(name string, size int64) error) error {
+	it := g.client.Bucket(g.bucket).Objects(ctx, &storage.Query{Prefix: g.prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(attrs.Name, g.prefix), "/")
+		if err := fn(rel, attrs.Size); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+func (g *gcsStorage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	return g.object(name).NewWriter(ctx), nil
+}
+
+// azblobStorage implements ExternalStorage on top of Azure Blob Storage,
+// addressed as azblob://container/prefix.
+type azblobStorage struct {
+	container azblob.ContainerURL
+	prefix    string
+}
+
+func newAzblobStorage(u *url.URL) (*azblobStorage, error) {
+	credential, err := azblob.NewSharedKeyCredential(os.Getenv("AZURE_STORAGE_ACCOUNT"), os.Getenv("AZURE_STORAGE_KEY"))
+	if err != nil {
+		return nil, errors.Annotate(err, "create azblob credential failed")
+	}
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	containerURL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", os.Getenv("AZURE_STORAGE_ACCOUNT"), u.Host))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &azblobStorage{
+		container: azblob.NewContainerURL(*containerURL, pipeline),
+		prefix:    strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (a *azblobStorage) blob(name string) azblob.BlockBlobURL {
+	return a.container.NewBlockBlobURL(filepath.Join(a.prefix, name))
+}
+
+func (a *azblobStorage) ReadRange(ctx context.Context, name string, offset, length int64) ([]byte, error) {
+	resp, err := a.blob(name).Download(ctx, offset, length, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return nil, errors.Trace(ErrFileNotExist)
+		}
+		return nil, errors.Annotatef(err, "download azblob %s failed", name)
+	}
+	body := resp.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	return data, errors.Trace(err)
+}
+
+func (a *azblobStorage) WalkDir(ctx context.Context, fn func(name string, size int64) error) error {
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := a.container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: a.prefix})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, blob := range resp.Segment.BlobItems {
+			rel := strings.TrimPrefix(strings.TrimPrefix(blob.Name, a.prefix), "/")
+			if err := fn(rel, *blob.Properties.ContentLength); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		marker = resp.NextMarker
+	}
+	return nil
+}
+
+func (a *azblobStorage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	return newAzblobWriter(ctx, a.blob(name)), nil
+}
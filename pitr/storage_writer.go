@@ -0,0 +1,67 @@
+package pitr
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// pipeWriter pipes everything written to it into an upload running in the
+// background, so ExternalStorage.Create can hand back a plain
+// io.WriteCloser without buffering the whole reduced output in memory.
+type pipeWriter struct {
+	w    *io.PipeWriter
+	done chan error
+}
+
+func newPipeWriter(upload func(r io.Reader) error) *pipeWriter {
+	r, w := io.Pipe()
+	pw := &pipeWriter{w: w, done: make(chan error, 1)}
+	go func() {
+		err := upload(r)
+		r.CloseWithError(err)
+		pw.done <- err
+	}()
+	return pw
+}
+
+func (p *pipeWriter) Write(data []byte) (int, error) {
+	return p.w.Write(data)
+}
+
+func (p *pipeWriter) Close() error {
+	if err := p.w.Close(); err != nil {
+		return err
+	}
+	return <-p.done
+}
+
+func newS3Writer(ctx context.Context, svc *s3.S3, bucket, key string) *pipeWriter {
+	uploader := s3manager.NewUploaderWithClient(svc)
+	return newPipeWriter(func(r io.Reader) error {
+		_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: &bucket,
+			Key:    &key,
+			Body:   r,
+		})
+		if err != nil {
+			log.Error("upload to s3 failed", zap.String("bucket", bucket), zap.String("key", key), zap.Error(err))
+		}
+		return err
+	})
+}
+
+func newAzblobWriter(ctx context.Context, blob azblob.BlockBlobURL) *pipeWriter {
+	return newPipeWriter(func(r io.Reader) error {
+		_, err := azblob.UploadStreamToBlockBlob(ctx, r, blob, azblob.UploadStreamToBlockBlobOptions{})
+		if err != nil {
+			log.Error("upload to azblob failed", zap.Error(err))
+		}
+		return err
+	})
+}
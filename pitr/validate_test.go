@@ -0,0 +1,152 @@
+package pitr
+
+import (
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"testing"
+)
+
+func tableInfo(id int64, name string) *model.TableInfo {
+	return &model.TableInfo{ID: id, Name: model.NewCIStr(name)}
+}
+
+func jobWithTable(id int64, typ model.ActionType, schema string, tableID int64, info *model.TableInfo) *model.Job {
+	job := newTestJob(id, typ, schema, "")
+	job.TableID = tableID
+	job.BinlogInfo.TableInfo = info
+	return job
+}
+
+func TestEffectiveTablesKeepsLatestCreateTable(t *testing.T) {
+	h := historyDDLHandler{ddlJobs: []*model.Job{
+		jobWithTable(1, model.ActionCreateTable, "db1", 10, tableInfo(10, "t1")),
+	}}
+
+	tables, err := effectiveTables(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].info.ID != 10 {
+		t.Fatalf("effectiveTables() = %+v, want a single table with id 10", tables)
+	}
+}
+
+func TestEffectiveTablesDropTableRemovesIt(t *testing.T) {
+	h := historyDDLHandler{ddlJobs: []*model.Job{
+		jobWithTable(1, model.ActionCreateTable, "db1", 10, tableInfo(10, "t1")),
+		jobWithTable(2, model.ActionDropTable, "db1", 10, nil),
+	}}
+
+	tables, err := effectiveTables(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Errorf("effectiveTables() = %+v, want no tables after a drop", tables)
+	}
+}
+
+func TestEffectiveTablesTruncateTableTracksNewID(t *testing.T) {
+	h := historyDDLHandler{ddlJobs: []*model.Job{
+		jobWithTable(1, model.ActionCreateTable, "db1", 10, tableInfo(10, "t1")),
+		// TruncateTable's job.TableID is the pre-truncate table; the new
+		// table it creates comes back with its own id in BinlogInfo.TableInfo.
+		jobWithTable(2, model.ActionTruncateTable, "db1", 10, tableInfo(11, "t1")),
+	}}
+
+	tables, err := effectiveTables(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].info.ID != 11 {
+		t.Fatalf("effectiveTables() = %+v, want only the post-truncate table (id 11)", tables)
+	}
+}
+
+func TestEffectiveTablesDropSchemaRemovesItsTablesOnly(t *testing.T) {
+	h := historyDDLHandler{ddlJobs: []*model.Job{
+		jobWithTable(1, model.ActionCreateTable, "db1", 10, tableInfo(10, "t1")),
+		jobWithTable(2, model.ActionCreateTable, "db2", 20, tableInfo(20, "t2")),
+		{ID: 3, Type: model.ActionDropSchema, SchemaName: "db1", BinlogInfo: &model.HistoryInfo{}},
+	}}
+
+	tables, err := effectiveTables(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].info.ID != 20 {
+		t.Fatalf("effectiveTables() = %+v, want only db2's table left", tables)
+	}
+}
+
+func TestCheckTableReplicablePKIsHandle(t *testing.T) {
+	info := tableInfo(1, "t1")
+	info.PKIsHandle = true
+
+	if _, _, ok := checkTableReplicable(info); !ok {
+		t.Error("a table with PKIsHandle should be replicable")
+	}
+}
+
+func TestCheckTableReplicableNoPKOrUniqueIndex(t *testing.T) {
+	info := tableInfo(1, "t1")
+
+	reason, _, ok := checkTableReplicable(info)
+	if ok || reason != reasonNoPKOrUK {
+		t.Errorf("checkTableReplicable() = (%v, ok=%v), want reasonNoPKOrUK", reason, ok)
+	}
+}
+
+func TestCheckTableReplicableUniqueNotNullIndex(t *testing.T) {
+	info := tableInfo(1, "t1")
+	col := &model.ColumnInfo{Name: model.NewCIStr("c1")}
+	col.Flag |= mysql.NotNullFlag
+	info.Columns = []*model.ColumnInfo{col}
+	info.Indices = []*model.IndexInfo{{
+		Unique:  true,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("c1")}},
+	}}
+
+	if _, _, ok := checkTableReplicable(info); !ok {
+		t.Error("a unique index over only not-null columns should make the table replicable")
+	}
+}
+
+func TestCheckTableReplicableUniqueNullableIndex(t *testing.T) {
+	info := tableInfo(1, "t1")
+	col := &model.ColumnInfo{Name: model.NewCIStr("c1")}
+	info.Columns = []*model.ColumnInfo{col}
+	info.Indices = []*model.IndexInfo{{
+		Unique:  true,
+		Columns: []*model.IndexColumn{{Name: model.NewCIStr("c1")}},
+	}}
+
+	reason, _, ok := checkTableReplicable(info)
+	if ok || reason != reasonNoPKOrUK {
+		t.Errorf("a unique index over a nullable column doesn't guarantee uniqueness, got (%v, ok=%v)", reason, ok)
+	}
+}
+
+func TestCheckTableReplicableGeneratedColumn(t *testing.T) {
+	info := tableInfo(1, "t1")
+	info.PKIsHandle = true
+	info.Columns = []*model.ColumnInfo{{Name: model.NewCIStr("c1"), GeneratedExprString: "c2 + 1"}}
+
+	reason, _, ok := checkTableReplicable(info)
+	if ok || reason != reasonGeneratedColumn {
+		t.Errorf("checkTableReplicable() = (%v, ok=%v), want reasonGeneratedColumn", reason, ok)
+	}
+}
+
+func TestCheckTableReplicableUnsupportedColumnType(t *testing.T) {
+	info := tableInfo(1, "t1")
+	info.PKIsHandle = true
+	col := &model.ColumnInfo{Name: model.NewCIStr("c1")}
+	col.Tp = mysql.TypeGeometry
+	info.Columns = []*model.ColumnInfo{col}
+
+	reason, _, ok := checkTableReplicable(info)
+	if ok || reason != reasonUnsupportedType {
+		t.Errorf("checkTableReplicable() = (%v, ok=%v), want reasonUnsupportedType", reason, ok)
+	}
+}